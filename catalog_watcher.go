@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CatalogWatcher keeps the proxy in sync with Consul's service catalog
+// without relying on an external orchestrator to call /v1/reconfigure on
+// every deploy. It long-polls /v1/catalog/services for the set of known
+// services and, for each one, starts a per-service goroutine that
+// long-polls /v1/health/service/<name> and (re)configures the proxy
+// whenever the service's `df.*` tags or its passing instances change. Both
+// polls are blocking queries: the last-seen X-Consul-Index is passed back
+// on the next request so Consul only replies once something changed.
+type CatalogWatcher struct {
+	BaseReconfigure
+	debounceInterval time.Duration
+
+	mu       sync.Mutex
+	watchers map[string]context.CancelFunc
+}
+
+// NewCatalogWatcher creates a CatalogWatcher bound to the given base
+// settings. debounceInterval is how long a service's tags must stay
+// unchanged before the watcher reconfigures the proxy for it, so that a
+// burst of catalog updates during a rolling deploy collapses into a
+// single Execute.
+func NewCatalogWatcher(base BaseReconfigure, debounceInterval time.Duration) *CatalogWatcher {
+	return &CatalogWatcher{
+		BaseReconfigure:  base,
+		debounceInterval: debounceInterval,
+		watchers:         map[string]context.CancelFunc{},
+	}
+}
+
+// Run blocks, long-polling Consul's catalog and starting/stopping
+// per-service watchers as services register and deregister. Callers
+// typically run it in its own goroutine alongside the HTTP server.
+func (w *CatalogWatcher) Run() error {
+	index := "0"
+	for {
+		services, newIndex, err := w.listServices(context.Background(), index)
+		if err != nil {
+			logPrintf("CatalogWatcher: could not list services: %s", err.Error())
+			time.Sleep(w.debounceInterval)
+			continue
+		}
+		index = newIndex
+		w.sync(services)
+	}
+}
+
+func (w *CatalogWatcher) sync(services map[string][]string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for name := range services {
+		if _, ok := w.watchers[name]; !ok {
+			ctx, cancel := context.WithCancel(context.Background())
+			w.watchers[name] = cancel
+			go w.watchService(ctx, name)
+		}
+	}
+	for name, cancel := range w.watchers {
+		if _, ok := services[name]; !ok {
+			cancel()
+			delete(w.watchers, name)
+			w.remove(name)
+		}
+	}
+}
+
+// watchService runs until ctx is cancelled, which sync does as soon as the
+// service disappears from the catalog. ctx is threaded all the way down
+// into the blocking HTTP call itself, so cancellation aborts an in-flight
+// long poll instead of waiting up to its 5m wait= timeout to notice.
+func (w *CatalogWatcher) watchService(ctx context.Context, name string) {
+	index := "0"
+	var debounce *time.Timer
+	for {
+		select {
+		case <-ctx.Done():
+			if debounce != nil {
+				debounce.Stop()
+			}
+			return
+		default:
+		}
+		tags, newIndex, err := w.serviceTags(ctx, name, index)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logPrintf("CatalogWatcher: could not watch service %s: %s", name, err.Error())
+			select {
+			case <-time.After(w.debounceInterval):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		index = newIndex
+		if debounce != nil {
+			debounce.Stop()
+		}
+		debounce = time.AfterFunc(w.debounceInterval, func() {
+			w.reconfigure(name, tags)
+		})
+	}
+}
+
+func (w *CatalogWatcher) reconfigure(name string, tags []string) {
+	sr, ok := serviceReconfigureFromTags(name, tags)
+	if !ok {
+		w.remove(name)
+		return
+	}
+	reconfigure := NewReconfigure(w.BaseReconfigure, sr)
+	if err := reconfigure.Execute([]string{}); err != nil {
+		logPrintf("CatalogWatcher: could not reconfigure %s: %s", name, err.Error())
+	}
+}
+
+func (w *CatalogWatcher) remove(name string) {
+	remove := Remove{ServiceName: name, TemplatesPath: w.TemplatesPath, ConfigsPath: w.ConfigsPath}
+	if err := remove.Execute([]string{}); err != nil {
+		logPrintf("CatalogWatcher: could not remove %s: %s", name, err.Error())
+	}
+}
+
+func (w *CatalogWatcher) listServices(ctx context.Context, index string) (map[string][]string, string, error) {
+	url := fmt.Sprintf("%s/v1/catalog/services?index=%s&wait=5m", addHttpIfNeeded(w.ConsulAddress), index)
+	body, newIndex, err := blockingGet(ctx, url)
+	if err != nil {
+		return nil, index, err
+	}
+	services := map[string][]string{}
+	if err := json.Unmarshal(body, &services); err != nil {
+		return nil, index, err
+	}
+	return services, newIndex, nil
+}
+
+func (w *CatalogWatcher) serviceTags(ctx context.Context, name, index string) ([]string, string, error) {
+	url := fmt.Sprintf("%s/v1/health/service/%s?index=%s&wait=5m", addHttpIfNeeded(w.ConsulAddress), name, index)
+	body, newIndex, err := blockingGet(ctx, url)
+	if err != nil {
+		return nil, index, err
+	}
+	var entries []struct {
+		Service struct {
+			Tags []string
+		}
+	}
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, index, err
+	}
+	tags := []string{}
+	for _, entry := range entries {
+		tags = append(tags, entry.Service.Tags...)
+	}
+	return tags, newIndex, nil
+}
+
+// blockingGet issues a Consul blocking query (long-polling via the `index`
+// parameter) and returns the body together with the X-Consul-Index header
+// to pass back in on the next call, shared by CatalogWatcher and
+// ConsulProvider. The request is bound to ctx so a cancellation aborts the
+// long poll in flight instead of only being noticed on the next call.
+func blockingGet(ctx context.Context, url string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "0", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "0", err
+	}
+	defer resp.Body.Close()
+	index := resp.Header.Get("X-Consul-Index")
+	if len(index) == 0 {
+		index = "0"
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, index, err
+	}
+	return body, index, nil
+}
+
+// serviceReconfigureFromTags translates a service's `df.*` catalog tags
+// (df.path, df.pathType, df.domain, df.skipCheck, df.color) into a
+// ServiceReconfigure. ok is false when the service carries no df.path
+// tag, meaning it isn't meant to be routed by the proxy at all.
+func serviceReconfigureFromTags(name string, tags []string) (sr ServiceReconfigure, ok bool) {
+	sr.ServiceName = name
+	for _, tag := range tags {
+		parts := strings.SplitN(tag, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := parts[0], parts[1]
+		switch key {
+		case "df.path":
+			sr.ServicePath = append(sr.ServicePath, value)
+		case "df.pathType":
+			sr.PathType = value
+		case "df.domain":
+			sr.ServiceDomain = value
+		case "df.color":
+			sr.ServiceColor = value
+		case "df.skipCheck":
+			sr.SkipCheck, _ = strconv.ParseBool(value)
+		}
+	}
+	return sr, len(sr.ServicePath) > 0
+}
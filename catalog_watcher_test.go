@@ -0,0 +1,62 @@
+// +build !integration
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CatalogWatcherTestSuite struct {
+	suite.Suite
+}
+
+func (s *CatalogWatcherTestSuite) Test_ServiceReconfigureFromTags_ParsesPath() {
+	sr, ok := serviceReconfigureFromTags("myService", []string{"df.path=/api/x"})
+
+	s.True(ok)
+	s.Equal("myService", sr.ServiceName)
+	s.Equal([]string{"/api/x"}, sr.ServicePath)
+}
+
+func (s *CatalogWatcherTestSuite) Test_ServiceReconfigureFromTags_ParsesAllDirectives() {
+	sr, ok := serviceReconfigureFromTags("myService", []string{
+		"df.path=/api/x",
+		"df.pathType=path_beg",
+		"df.domain=foo.com",
+		"df.skipCheck=true",
+		"df.color=blue",
+	})
+
+	s.True(ok)
+	s.Equal([]string{"/api/x"}, sr.ServicePath)
+	s.Equal("path_beg", sr.PathType)
+	s.Equal("foo.com", sr.ServiceDomain)
+	s.True(sr.SkipCheck)
+	s.Equal("blue", sr.ServiceColor)
+}
+
+func (s *CatalogWatcherTestSuite) Test_ServiceReconfigureFromTags_CollectsMultiplePaths() {
+	sr, ok := serviceReconfigureFromTags("myService", []string{"df.path=/api/x", "df.path=/api/y"})
+
+	s.True(ok)
+	s.Equal([]string{"/api/x", "/api/y"}, sr.ServicePath)
+}
+
+func (s *CatalogWatcherTestSuite) Test_ServiceReconfigureFromTags_IgnoresUnrelatedTags() {
+	sr, ok := serviceReconfigureFromTags("myService", []string{"df.path=/api/x", "some.other.tag=value"})
+
+	s.True(ok)
+	s.Equal([]string{"/api/x"}, sr.ServicePath)
+}
+
+func (s *CatalogWatcherTestSuite) Test_ServiceReconfigureFromTags_ReturnsFalse_WhenNoPathTag() {
+	_, ok := serviceReconfigureFromTags("myService", []string{"df.domain=foo.com"})
+
+	s.False(ok)
+}
+
+func TestCatalogWatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(CatalogWatcherTestSuite))
+}
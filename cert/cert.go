@@ -0,0 +1,144 @@
+// Package cert obtains and tracks the TLS certificates docker-flow-proxy
+// terminates HTTPS with, either issued automatically via ACME or provided
+// as a static PEM file, and keeps HAProxy's crt-list in sync with them.
+package cert
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// AutoValue is the serviceCert value that requests an ACME-issued
+// certificate instead of a static PEM file.
+const AutoValue = "auto"
+
+// Entry describes one certificate the Manager knows about.
+type Entry struct {
+	Domain string
+	Path   string
+	Auto   bool
+}
+
+// Manager obtains and refreshes certificates for services declaring
+// serviceCert=auto (or serviceCert=/path/to/pem for a static cert), and
+// renders the crt-list HAProxy's `bind ... crt <path>` reads from.
+type Manager struct {
+	dir string
+
+	mu       sync.Mutex
+	entries  map[string]Entry
+	autocert *autocert.Manager
+}
+
+// NewManager creates a Manager that persists issued certificates and ACME
+// account keys under dir.
+func NewManager(dir string) *Manager {
+	m := &Manager{dir: dir, entries: map[string]Entry{}}
+	m.autocert = &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(dir),
+		HostPolicy: m.hostPolicy,
+	}
+	return m
+}
+
+// Add registers domain's certificate, obtaining it from ACME when value is
+// AutoValue or using the PEM file at value otherwise, then rewrites the
+// crt-list.
+func (m *Manager) Add(domain, value string) error {
+	if value == AutoValue {
+		// Register the entry before calling GetCertificate: autocert calls
+		// HostPolicy synchronously before issuing, and HostPolicy needs to
+		// see domain as known. Crucially, this must happen without m.mu
+		// held across the call, since GetCertificate -> HostPolicy ->
+		// m.hostPolicy re-acquires the same, non-reentrant mutex on this
+		// goroutine.
+		m.mu.Lock()
+		m.entries[domain] = Entry{Domain: domain, Path: fmt.Sprintf("%s/%s", m.dir, domain), Auto: true}
+		m.mu.Unlock()
+
+		if _, err := m.autocert.GetCertificate(autocertHello(domain)); err != nil {
+			m.mu.Lock()
+			delete(m.entries, domain)
+			m.mu.Unlock()
+			return fmt.Errorf("Could not obtain a certificate for %s\n%s", domain, err.Error())
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.writeCrtList()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[domain] = Entry{Domain: domain, Path: value, Auto: false}
+	return m.writeCrtList()
+}
+
+// Remove forgets domain's certificate and rewrites the crt-list.
+func (m *Manager) Remove(domain string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, domain)
+	return m.writeCrtList()
+}
+
+// List returns every certificate the Manager currently knows about, for
+// the /v1/docker-flow-proxy/certs inspection endpoint.
+func (m *Manager) List() []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := []Entry{}
+	for _, e := range m.entries {
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// CrtListPath is where HAProxy's `bind *:443 ssl crt <path>` should point.
+func (m *Manager) CrtListPath() string {
+	return fmt.Sprintf("%s/crt-list.txt", m.dir)
+}
+
+func (m *Manager) writeCrtList() error {
+	lines := []string{}
+	for _, e := range m.entries {
+		lines = append(lines, fmt.Sprintf("%s %s", e.Path, e.Domain))
+	}
+	content := strings.Join(lines, "\n")
+	if err := ioutil.WriteFile(m.CrtListPath(), []byte(content), 0664); err != nil {
+		return fmt.Errorf("Could not write the crt-list to %s\n%s", m.CrtListPath(), err.Error())
+	}
+	return nil
+}
+
+// autocertHello builds a ClientHelloInfo that declares ECDSA support, so
+// autocert.Manager issues (and caches under autocert.DirCache) the default
+// ECDSA certificate for domain rather than its legacy RSA fallback. This
+// must stay in sync with the path Add records in Entry.Path: autocert's
+// cache key is domain for an ECDSA cert, but domain+"+rsa" for an RSA one
+// (see certKey.String() in golang.org/x/crypto/acme/autocert), and with no
+// SignatureSchemes/SupportedCurves/CipherSuites set at all it defaults to
+// RSA.
+func autocertHello(domain string) *tls.ClientHelloInfo {
+	return &tls.ClientHelloInfo{
+		ServerName:      domain,
+		SupportedCurves: []tls.CurveID{tls.CurveP256},
+		CipherSuites:    []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	}
+}
+
+func (m *Manager) hostPolicy(ctx context.Context, host string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.entries[host]; ok {
+		return nil
+	}
+	return fmt.Errorf("%s is not a known service domain", host)
+}
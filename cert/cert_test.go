@@ -0,0 +1,112 @@
+package cert
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+type CertTestSuite struct {
+	suite.Suite
+	Dir string
+}
+
+func (s *CertTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "df-cert")
+	s.NoError(err)
+	s.Dir = dir
+}
+
+func (s *CertTestSuite) TearDownTest() {
+	os.RemoveAll(s.Dir)
+}
+
+func (s *CertTestSuite) Test_Add_StaticCert_WritesCrtList() {
+	m := NewManager(s.Dir)
+
+	err := m.Add("foo.com", "/path/to/foo.pem")
+
+	s.NoError(err)
+	content, readErr := ioutil.ReadFile(m.CrtListPath())
+	s.NoError(readErr)
+	s.Equal("/path/to/foo.pem foo.com", string(content))
+}
+
+func (s *CertTestSuite) Test_Add_StaticCert_RecordsEntry() {
+	m := NewManager(s.Dir)
+	m.Add("foo.com", "/path/to/foo.pem")
+
+	entries := m.List()
+
+	s.Equal(1, len(entries))
+	s.Equal("foo.com", entries[0].Domain)
+	s.Equal("/path/to/foo.pem", entries[0].Path)
+	s.False(entries[0].Auto)
+}
+
+func (s *CertTestSuite) Test_Remove_ForgetsEntry() {
+	m := NewManager(s.Dir)
+	m.Add("foo.com", "/path/to/foo.pem")
+
+	err := m.Remove("foo.com")
+
+	s.NoError(err)
+	s.Equal(0, len(m.List()))
+}
+
+func (s *CertTestSuite) Test_Add_AutoCert_DoesNotDeadlock() {
+	m := NewManager(s.Dir)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Add("foo.com", AutoValue)
+	}()
+
+	select {
+	case <-done:
+		// GetCertificate will fail without real ACME/network access, but
+		// Add must still return instead of hanging forever on its own
+		// mutex via HostPolicy.
+	case <-time.After(5 * time.Second):
+		s.Fail("Add(AutoValue) did not return within 5s, likely self-deadlocked on m.mu")
+	}
+}
+
+func (s *CertTestSuite) Test_Add_AutoCert_PathMatchesAutocertCacheKey() {
+	m := NewManager(s.Dir)
+	m.mu.Lock()
+	m.entries["foo.com"] = Entry{Domain: "foo.com", Path: fmt.Sprintf("%s/%s", s.Dir, "foo.com"), Auto: true}
+	m.mu.Unlock()
+
+	// autocert.DirCache's cache key for the default (ECDSA) cert it issues
+	// via autocertHello is the bare domain, with no ".pem" suffix. Writing
+	// through DirCache directly and reading back via Entry.Path pins that
+	// assumption without needing real ACME/network access.
+	cache := autocert.DirCache(s.Dir)
+	err := cache.Put(context.Background(), "foo.com", []byte("fake-cert-bundle"))
+	s.NoError(err)
+
+	entries := m.List()
+	s.Equal(1, len(entries))
+	content, readErr := ioutil.ReadFile(entries[0].Path)
+	s.NoError(readErr)
+	s.Equal("fake-cert-bundle", string(content))
+}
+
+func (s *CertTestSuite) Test_HostPolicy_RejectsUnknownDomain() {
+	m := NewManager(s.Dir)
+	m.Add("foo.com", "/path/to/foo.pem")
+
+	s.NoError(m.hostPolicy(nil, "foo.com"))
+	s.Error(m.hostPolicy(nil, "bar.com"))
+}
+
+func TestCertTestSuite(t *testing.T) {
+	suite.Run(t, new(CertTestSuite))
+}
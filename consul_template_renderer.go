@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// ConsulTemplateRenderer renders a service's templates by writing them to
+// ServiceTemplateFeFilename/ServiceTemplateBeFilename and shelling out to
+// the consul-template binary, exactly as Reconfigure.Execute did before
+// template rendering became pluggable. It's the default renderer.TemplateRenderer.
+type ConsulTemplateRenderer struct{}
+
+// consulTemplateRenderMu serializes writeTemplateFiles+runConsulTemplate
+// across every ConsulTemplateRenderer call. The two steps go through the
+// same fixed ServiceTemplateFeFilename/BeFilename scratch files regardless
+// of which service is being rendered, and CatalogWatcher/the provider
+// aggregator now call Execute concurrently from per-service goroutines, so
+// without this lock two services reconfiguring around the same moment
+// would clobber each other's scratch file mid-render.
+var consulTemplateRenderMu sync.Mutex
+
+// Render implements renderer.TemplateRenderer.
+func (ConsulTemplateRenderer) Render(consulAddress, templatesPath, configsPath, serviceName, front, back string) error {
+	consulTemplateRenderMu.Lock()
+	defer consulTemplateRenderMu.Unlock()
+	if err := writeTemplateFiles(templatesPath, front, back); err != nil {
+		return err
+	}
+	return runConsulTemplate(consulAddress, templatesPath, serviceName)
+}
+
+func writeTemplateFiles(templatesPath, front, back string) error {
+	fePath := fmt.Sprintf("%s/%s", templatesPath, ServiceTemplateFeFilename)
+	bePath := fmt.Sprintf("%s/%s", templatesPath, ServiceTemplateBeFilename)
+	if err := writeConsulTemplateFile(fePath, []byte(front), 0664); err != nil {
+		return fmt.Errorf("Could not write the frontend template to %s\n%s", fePath, err.Error())
+	}
+	if err := writeConsulTemplateFile(bePath, []byte(back), 0664); err != nil {
+		return fmt.Errorf("Could not write the backend template to %s\n%s", bePath, err.Error())
+	}
+	return nil
+}
+
+func runConsulTemplate(consulAddress, templatesPath, serviceName string) error {
+	address := stripScheme(consulAddress)
+	sides := []struct {
+		filename string
+		suffix   string
+	}{
+		{ServiceTemplateFeFilename, "fe"},
+		{ServiceTemplateBeFilename, "be"},
+	}
+	for _, side := range sides {
+		cmdArgs := []string{
+			"consul-template",
+			"-consul", address,
+			"-template", fmt.Sprintf(
+				"%s/%s:%s/%s-%s.cfg",
+				templatesPath, side.filename, templatesPath, serviceName, side.suffix,
+			),
+			"-once",
+		}
+		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+		if err := cmdRunConsul(cmd); err != nil {
+			return fmt.Errorf("Could not run consul-template: %s\n%s", strings.Join(cmdArgs, " "), err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// +build !integration
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConsulTemplateRendererTestSuite struct {
+	suite.Suite
+	TemplatesPath string
+	ConsulAddress string
+	renderer      ConsulTemplateRenderer
+}
+
+func (s *ConsulTemplateRendererTestSuite) SetupTest() {
+	s.TemplatesPath = "test_configs/tmpl"
+	s.ConsulAddress = "http://my-consul.com"
+	s.renderer = ConsulTemplateRenderer{}
+	cmdRunConsul = func(cmd *exec.Cmd) error {
+		return nil
+	}
+	writeConsulTemplateFile = func(fileName string, data []byte, perm os.FileMode) error {
+		return nil
+	}
+}
+
+func (s *ConsulTemplateRendererTestSuite) Test_Render_WritesTemplateFiles() {
+	var actual []string
+	writeConsulTemplateFile = func(fileName string, data []byte, perm os.FileMode) error {
+		actual = append(actual, fileName)
+		return nil
+	}
+
+	s.renderer.Render(s.ConsulAddress, s.TemplatesPath, "", "myService", "front", "back")
+
+	s.Equal(fmt.Sprintf("%s/%s", s.TemplatesPath, ServiceTemplateFeFilename), actual[0])
+	s.Equal(fmt.Sprintf("%s/%s", s.TemplatesPath, ServiceTemplateBeFilename), actual[1])
+}
+
+func (s *ConsulTemplateRendererTestSuite) Test_Render_RunsConsulTemplate() {
+	var actual [][]string
+	cmdRunConsul = func(cmd *exec.Cmd) error {
+		actual = append(actual, cmd.Args)
+		return nil
+	}
+
+	s.renderer.Render(s.ConsulAddress, s.TemplatesPath, "", "myService", "front", "back")
+
+	s.Equal(2, len(actual))
+	s.True(strings.Contains(actual[0][4], "fe.ctmpl"))
+	s.True(strings.Contains(actual[1][4], "be.ctmpl"))
+}
+
+func (s *ConsulTemplateRendererTestSuite) Test_Render_ReturnsError_WhenWriteFails() {
+	writeConsulTemplateFile = func(fileName string, data []byte, perm os.FileMode) error {
+		return fmt.Errorf("This is an error")
+	}
+
+	err := s.renderer.Render(s.ConsulAddress, s.TemplatesPath, "", "myService", "front", "back")
+
+	s.Error(err)
+}
+
+func (s *ConsulTemplateRendererTestSuite) Test_Render_ReturnsError_WhenConsulTemplateFails() {
+	cmdRunConsul = func(cmd *exec.Cmd) error {
+		return fmt.Errorf("This is an error")
+	}
+
+	err := s.renderer.Render(s.ConsulAddress, s.TemplatesPath, "", "myService", "front", "back")
+
+	s.Error(err)
+}
+
+func (s *ConsulTemplateRendererTestSuite) Test_Render_SerializesConcurrentCalls() {
+	var inFlight, maxInFlight int32
+	writeConsulTemplateFile = func(fileName string, data []byte, perm os.FileMode) error {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.renderer.Render(s.ConsulAddress, s.TemplatesPath, "", fmt.Sprintf("service-%d", i), "front", "back")
+		}(i)
+	}
+	wg.Wait()
+
+	s.Equal(int32(1), maxInFlight)
+}
+
+func TestConsulTemplateRendererTestSuite(t *testing.T) {
+	suite.Run(t, new(ConsulTemplateRendererTestSuite))
+}
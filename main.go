@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/tlebovic/docker-flow-proxy/cert"
+)
+
+func main() {
+	consulAddress := flag.String("consul-address", os.Getenv("CONSUL_ADDRESS"), "The address of the Consul instance used to store proxy configuration")
+	templatesPath := flag.String("templates-path", "/templates", "The path to the directory with consul-template source files")
+	configsPath := flag.String("configs-path", "/cfg", "The path to the directory with HAProxy configuration files")
+	certsPath := flag.String("certs-path", "/certs", "The path to the directory where issued certificates and ACME account keys are persisted")
+	watchCatalog := flag.Bool("watch-catalog", os.Getenv("WATCH_CATALOG") == "true", "Watch Consul's catalog and reconfigure the proxy automatically based on df.* service tags, instead of waiting for /v1/reconfigure requests")
+	consulProvider := flag.Bool("consul-provider", os.Getenv("CONSUL_PROVIDER") == "true", "Watch Consul's catalog and the docker-flow/<service> KV data and reconfigure the proxy automatically. Opt-in and independent of -watch-catalog, which reconfigures from catalog tags instead")
+	fileProviderPath := flag.String("file-provider-path", os.Getenv("FILE_PROVIDER_PATH"), "Watch this directory for YAML/JSON service rule files and reconfigure the proxy automatically. Backend instances are still looked up in Consul")
+	dockerProvider := flag.Bool("docker-provider", os.Getenv("DOCKER_PROVIDER") == "true", "Watch the local Docker daemon for containers carrying com.df.* labels and reconfigure the proxy automatically")
+	flag.Parse()
+
+	certManager = cert.NewManager(*certsPath)
+
+	base := BaseReconfigure{
+		ConsulAddress: *consulAddress,
+		TemplatesPath: *templatesPath,
+		ConfigsPath:   *configsPath,
+	}
+	if *watchCatalog {
+		go func() {
+			if err := NewCatalogWatcher(base, 2*time.Second).Run(); err != nil {
+				logPrintf("CatalogWatcher stopped: %s", err.Error())
+			}
+		}()
+	}
+
+	var providers []Provider
+	if *consulProvider && len(base.ConsulAddress) > 0 {
+		providers = append(providers, NewConsulProvider(base))
+	}
+	if len(*fileProviderPath) > 0 {
+		providers = append(providers, NewFileProvider(*fileProviderPath))
+	}
+	if *dockerProvider {
+		p, err := NewDockerProvider()
+		if err != nil {
+			logPrintf("%s", err.Error())
+		} else {
+			providers = append(providers, p)
+		}
+	}
+	if len(providers) > 0 {
+		runProviders(context.Background(), base, providers...)
+	}
+
+	if err := NewServer(base).Execute(); err != nil {
+		logPrintf("%s", err.Error())
+		os.Exit(1)
+	}
+}
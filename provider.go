@@ -0,0 +1,23 @@
+package main
+
+import "context"
+
+// ProviderID identifies the service a Provider's Events channel is
+// notifying about.
+type ProviderID struct {
+	ServiceName string
+}
+
+// Provider is implemented by anything that can discover services to route
+// and notify the main loop when one of them changes, so the proxy can run
+// without depending on an external orchestrator calling /v1/reconfigure
+// for every deploy. This mirrors the provider model used by
+// traefik/reproxy: ConsulProvider, FileProvider and DockerProvider all
+// satisfy it, and the main loop treats them interchangeably.
+type Provider interface {
+	// Events returns a channel of ProviderIDs, one per service that
+	// changed, closed once ctx is cancelled.
+	Events(ctx context.Context) <-chan ProviderID
+	// List returns every service the provider currently knows about.
+	List() ([]ServiceReconfigure, error)
+}
@@ -0,0 +1,41 @@
+package main
+
+import "context"
+
+// runProviders fans in events from every configured Provider and
+// reconfigures the proxy for whichever service each event names, looked up
+// via that same Provider's List(). This lets multiple sources (Consul,
+// flat files, Docker labels) feed the same Execute pipeline the HTTP API
+// and CatalogWatcher already use, without an external orchestrator having
+// to call /v1/reconfigure on every deploy.
+func runProviders(ctx context.Context, base BaseReconfigure, providers ...Provider) {
+	for _, p := range providers {
+		go func(p Provider) {
+			for id := range p.Events(ctx) {
+				reconfigureFromProvider(base, p, id)
+			}
+		}(p)
+	}
+}
+
+func reconfigureFromProvider(base BaseReconfigure, p Provider, id ProviderID) {
+	services, err := p.List()
+	if err != nil {
+		logPrintf("Could not list services: %s", err.Error())
+		return
+	}
+	for _, sr := range services {
+		if sr.ServiceName != id.ServiceName {
+			continue
+		}
+		reconfigure := NewReconfigure(base, sr)
+		if err := reconfigure.Execute([]string{}); err != nil {
+			logPrintf("Could not reconfigure %s: %s", sr.ServiceName, err.Error())
+		}
+		return
+	}
+	remove := Remove{ServiceName: id.ServiceName, TemplatesPath: base.TemplatesPath, ConfigsPath: base.ConfigsPath}
+	if err := remove.Execute([]string{}); err != nil {
+		logPrintf("Could not remove %s: %s", id.ServiceName, err.Error())
+	}
+}
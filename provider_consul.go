@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ConsulProvider discovers services from Consul's catalog and the
+// docker-flow/<service> KV data Reconfigure.Execute writes, i.e. today's
+// behavior, exposed as a Provider so it can be combined with FileProvider
+// and DockerProvider in the same main loop.
+type ConsulProvider struct {
+	BaseReconfigure
+}
+
+// NewConsulProvider creates a ConsulProvider bound to the given base
+// settings.
+func NewConsulProvider(base BaseReconfigure) *ConsulProvider {
+	return &ConsulProvider{BaseReconfigure: base}
+}
+
+// List implements Provider.
+func (p *ConsulProvider) List() ([]ServiceReconfigure, error) {
+	r := Reconfigure{BaseReconfigure: p.BaseReconfigure}
+	address := addHttpIfNeeded(p.ConsulAddress)
+	names, err := r.getCatalogServices(address)
+	if err != nil {
+		return nil, err
+	}
+	services := []ServiceReconfigure{}
+	for _, name := range names {
+		sr, err := r.getServiceFromConsul(address, name)
+		if err != nil {
+			// The service has no docker-flow-proxy data stored in Consul.
+			continue
+		}
+		services = append(services, sr)
+	}
+	return services, nil
+}
+
+// Events implements Provider, long-polling /v1/catalog/services and
+// reporting every known service name each time the catalog changes.
+func (p *ConsulProvider) Events(ctx context.Context) <-chan ProviderID {
+	out := make(chan ProviderID)
+	go func() {
+		defer close(out)
+		address := addHttpIfNeeded(p.ConsulAddress)
+		index := "0"
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			url := fmt.Sprintf("%s/v1/catalog/services?index=%s&wait=5m", address, index)
+			body, newIndex, err := blockingGet(ctx, url)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				logPrintf("ConsulProvider: could not list services: %s", err.Error())
+				time.Sleep(2 * time.Second)
+				continue
+			}
+			index = newIndex
+			names := map[string][]string{}
+			if err := json.Unmarshal(body, &names); err != nil {
+				continue
+			}
+			for name := range names {
+				select {
+				case out <- ProviderID{ServiceName: name}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
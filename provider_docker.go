@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/client"
+)
+
+// dockerClient is the subset of *client.Client DockerProvider needs, so
+// tests can fake the Docker daemon instead of running against a real
+// socket.
+type dockerClient interface {
+	ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error)
+	Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error)
+}
+
+// DockerProvider discovers services from the labels of containers running
+// on the local Docker daemon (com.df.servicePath, com.df.serviceDomain,
+// ...), the same idea as FileProvider but sourced from Docker instead of
+// flat files.
+type DockerProvider struct {
+	client dockerClient
+}
+
+// NewDockerProvider connects to the local Docker socket.
+func NewDockerProvider() (*DockerProvider, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("Could not connect to the Docker socket\n%s", err.Error())
+	}
+	return &DockerProvider{client: cli}, nil
+}
+
+// List implements Provider.
+func (p *DockerProvider) List() ([]ServiceReconfigure, error) {
+	containers, err := p.client.ContainerList(context.Background(), types.ContainerListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("Could not list Docker containers\n%s", err.Error())
+	}
+	services := []ServiceReconfigure{}
+	for _, c := range containers {
+		if sr, ok := serviceReconfigureFromLabels(c.Labels); ok {
+			services = append(services, sr)
+		}
+	}
+	return services, nil
+}
+
+// Events implements Provider, streaming the local Docker daemon's event
+// feed and reporting the service named by whichever container changed. The
+// Docker client closes both its channels on any stream error, so the feed
+// is re-subscribed after a short backoff instead of letting one daemon
+// hiccup permanently kill the watch.
+func (p *DockerProvider) Events(ctx context.Context) <-chan ProviderID {
+	out := make(chan ProviderID)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			if !p.streamEvents(ctx, out) {
+				return
+			}
+			logPrintf("DockerProvider: event stream closed, reconnecting")
+			select {
+			case <-time.After(2 * time.Second):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// streamEvents relays a single Docker event subscription to out until it
+// closes or ctx is done. It returns false when the caller should stop
+// altogether (ctx done) and true when the subscription merely ended and
+// should be retried.
+func (p *DockerProvider) streamEvents(ctx context.Context, out chan<- ProviderID) bool {
+	msgs, errs := p.client.Events(ctx, types.EventsOptions{})
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case msg, ok := <-msgs:
+			if !ok {
+				return true
+			}
+			if msg.Type != events.ContainerEventType {
+				continue
+			}
+			name := msg.Actor.Attributes["com.df.serviceName"]
+			if len(name) == 0 {
+				name = msg.Actor.Attributes["name"]
+			}
+			select {
+			case out <- ProviderID{ServiceName: name}:
+			case <-ctx.Done():
+				return false
+			}
+		case err, ok := <-errs:
+			if !ok {
+				return true
+			}
+			logPrintf("DockerProvider: event stream error: %s", err.Error())
+		}
+	}
+}
+
+// serviceReconfigureFromLabels translates a container's com.df.* labels
+// into a ServiceReconfigure. ok is false when the container carries no
+// com.df.servicePath label, meaning it isn't meant to be routed at all.
+func serviceReconfigureFromLabels(labels map[string]string) (sr ServiceReconfigure, ok bool) {
+	path := labels["com.df.servicePath"]
+	if len(path) == 0 {
+		return ServiceReconfigure{}, false
+	}
+	sr.ServiceName = labels["com.df.serviceName"]
+	sr.ServicePath = strings.Split(path, ",")
+	sr.ServiceDomain = labels["com.df.serviceDomain"]
+	sr.ServiceColor = labels["com.df.serviceColor"]
+	sr.PathType = labels["com.df.pathType"]
+	if skip, err := strconv.ParseBool(labels["com.df.skipCheck"]); err == nil {
+		sr.SkipCheck = skip
+	}
+	return sr, len(sr.ServiceName) > 0
+}
@@ -0,0 +1,105 @@
+// +build !integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeDockerClient lets tests drive DockerProvider.Events without a real
+// Docker daemon. Each call to Events pops the next scripted subscription.
+type fakeDockerClient struct {
+	subscriptions []func() (<-chan events.Message, <-chan error)
+	calls         int
+}
+
+func (f *fakeDockerClient) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	return nil, nil
+}
+
+func (f *fakeDockerClient) Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error) {
+	i := f.calls
+	f.calls++
+	if i >= len(f.subscriptions) {
+		msgs := make(chan events.Message)
+		errs := make(chan error)
+		return msgs, errs
+	}
+	return f.subscriptions[i]()
+}
+
+type ProviderDockerTestSuite struct {
+	suite.Suite
+}
+
+func (s *ProviderDockerTestSuite) Test_ServiceReconfigureFromLabels_ParsesLabels() {
+	labels := map[string]string{
+		"com.df.serviceName":   "myService",
+		"com.df.servicePath":   "/api/x,/api/y",
+		"com.df.serviceDomain": "foo.com",
+		"com.df.serviceColor":  "blue",
+		"com.df.pathType":      "path_beg",
+		"com.df.skipCheck":     "true",
+	}
+
+	sr, ok := serviceReconfigureFromLabels(labels)
+
+	s.True(ok)
+	s.Equal("myService", sr.ServiceName)
+	s.Equal([]string{"/api/x", "/api/y"}, sr.ServicePath)
+	s.Equal("foo.com", sr.ServiceDomain)
+	s.Equal("blue", sr.ServiceColor)
+	s.Equal("path_beg", sr.PathType)
+	s.True(sr.SkipCheck)
+}
+
+func (s *ProviderDockerTestSuite) Test_ServiceReconfigureFromLabels_ReturnsFalse_WhenNoServicePathLabel() {
+	_, ok := serviceReconfigureFromLabels(map[string]string{"com.df.serviceName": "myService"})
+
+	s.False(ok)
+}
+
+func (s *ProviderDockerTestSuite) Test_Events_ReconnectsAfterStreamCloses() {
+	closedSub := func() (<-chan events.Message, <-chan error) {
+		msgs := make(chan events.Message)
+		errs := make(chan error)
+		close(msgs)
+		close(errs)
+		return msgs, errs
+	}
+	liveMsgs := make(chan events.Message, 1)
+	liveErrs := make(chan error)
+	liveMsgs <- events.Message{
+		Type:  events.ContainerEventType,
+		Actor: events.Actor{Attributes: map[string]string{"com.df.serviceName": "myService"}},
+	}
+	fake := &fakeDockerClient{
+		subscriptions: []func() (<-chan events.Message, <-chan error){
+			closedSub,
+			func() (<-chan events.Message, <-chan error) { return liveMsgs, liveErrs },
+		},
+	}
+	p := &DockerProvider{client: fake}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := p.Events(ctx)
+
+	select {
+	case id := <-out:
+		s.Equal("myService", id.ServiceName)
+	case <-time.After(5 * time.Second):
+		s.Fail("Events did not reconnect and report the service after the first stream closed")
+	}
+	s.True(fake.calls >= 2)
+}
+
+func TestProviderDockerTestSuite(t *testing.T) {
+	suite.Run(t, new(ProviderDockerTestSuite))
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// FileProvider discovers services from YAML/JSON rule files in a
+// directory instead of Consul's catalog. The rule files carry no instance
+// addresses: Reconfigure.Execute still looks a service's instances up in
+// Consul when rendering its backend, and still stores its settings there.
+type FileProvider struct {
+	Dir string
+
+	mu          sync.Mutex
+	serviceName map[string]string // rule file path -> the ServiceName it last held
+}
+
+// NewFileProvider creates a FileProvider watching dir.
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{Dir: dir}
+}
+
+// fileRule is the on-disk shape of a single service's routing rules,
+// understood as either YAML or JSON depending on the file's extension.
+type fileRule struct {
+	ServiceName   string   `yaml:"serviceName" json:"serviceName"`
+	ServicePath   []string `yaml:"servicePath" json:"servicePath"`
+	ServiceDomain string   `yaml:"serviceDomain" json:"serviceDomain"`
+	ServiceColor  string   `yaml:"serviceColor" json:"serviceColor"`
+	PathType      string   `yaml:"pathType" json:"pathType"`
+	SkipCheck     bool     `yaml:"skipCheck" json:"skipCheck"`
+}
+
+// List implements Provider.
+func (p *FileProvider) List() ([]ServiceReconfigure, error) {
+	entries, err := ioutil.ReadDir(p.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the file provider directory %s\n%s", p.Dir, err.Error())
+	}
+	services := []ServiceReconfigure{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		sr, err := p.parseFile(filepath.Join(p.Dir, entry.Name()))
+		if err != nil {
+			logPrintf("FileProvider: could not parse %s: %s", entry.Name(), err.Error())
+			continue
+		}
+		services = append(services, sr)
+	}
+	return services, nil
+}
+
+// Events implements Provider, watching Dir with fsnotify and reporting the
+// service named by whichever file changed.
+func (p *FileProvider) Events(ctx context.Context) <-chan ProviderID {
+	out := make(chan ProviderID)
+	go func() {
+		defer close(out)
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			logPrintf("FileProvider: could not start watcher: %s", err.Error())
+			return
+		}
+		defer watcher.Close()
+		if err := watcher.Add(p.Dir); err != nil {
+			logPrintf("FileProvider: could not watch %s: %s", p.Dir, err.Error())
+			return
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				serviceName, ok := p.serviceNameForEvent(event)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- ProviderID{ServiceName: serviceName}:
+				case <-ctx.Done():
+					return
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logPrintf("FileProvider: watcher error: %s", err.Error())
+			}
+		}
+	}()
+	return out
+}
+
+// serviceNameForEvent resolves the ServiceName an fsnotify event is about.
+// For a Create/Write it's read straight off the rule file, and remembered
+// so a later Remove (when the file is already gone and can no longer be
+// parsed) can still be resolved to the same name - otherwise deleting a
+// service's rule file would never reach reconfigureFromProvider's
+// Remove.Execute, leaving the stale backend in HAProxy's config forever.
+func (p *FileProvider) serviceNameForEvent(event fsnotify.Event) (string, bool) {
+	if event.Op&fsnotify.Remove != 0 {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		name, ok := p.serviceName[event.Name]
+		delete(p.serviceName, event.Name)
+		return name, ok
+	}
+	sr, err := p.parseFile(event.Name)
+	if err != nil {
+		return "", false
+	}
+	p.mu.Lock()
+	if p.serviceName == nil {
+		p.serviceName = map[string]string{}
+	}
+	p.serviceName[event.Name] = sr.ServiceName
+	p.mu.Unlock()
+	return sr.ServiceName, true
+}
+
+func (p *FileProvider) parseFile(path string) (ServiceReconfigure, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ServiceReconfigure{}, err
+	}
+	var rule fileRule
+	if strings.HasSuffix(path, ".json") {
+		err = json.Unmarshal(content, &rule)
+	} else {
+		err = yaml.Unmarshal(content, &rule)
+	}
+	if err != nil {
+		return ServiceReconfigure{}, err
+	}
+	return ServiceReconfigure{
+		ServiceName:   rule.ServiceName,
+		ServicePath:   rule.ServicePath,
+		ServiceDomain: rule.ServiceDomain,
+		ServiceColor:  rule.ServiceColor,
+		PathType:      rule.PathType,
+		SkipCheck:     rule.SkipCheck,
+	}, nil
+}
@@ -0,0 +1,95 @@
+// +build !integration
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ProviderFileTestSuite struct {
+	suite.Suite
+	Dir string
+}
+
+func (s *ProviderFileTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "df-file-provider")
+	s.NoError(err)
+	s.Dir = dir
+}
+
+func (s *ProviderFileTestSuite) TearDownTest() {
+	os.RemoveAll(s.Dir)
+}
+
+func (s *ProviderFileTestSuite) Test_List_ReadsYamlRules() {
+	content := []byte("serviceName: myService\nservicePath:\n  - /api/x\nserviceDomain: foo.com\n")
+	s.NoError(ioutil.WriteFile(s.Dir+"/myService.yml", content, 0644))
+
+	services, err := NewFileProvider(s.Dir).List()
+
+	s.NoError(err)
+	s.Equal(1, len(services))
+	s.Equal("myService", services[0].ServiceName)
+	s.Equal([]string{"/api/x"}, services[0].ServicePath)
+	s.Equal("foo.com", services[0].ServiceDomain)
+}
+
+func (s *ProviderFileTestSuite) Test_List_ReadsJsonRules() {
+	content := []byte(`{"serviceName":"myService","servicePath":["/api/x"]}`)
+	s.NoError(ioutil.WriteFile(s.Dir+"/myService.json", content, 0644))
+
+	services, err := NewFileProvider(s.Dir).List()
+
+	s.NoError(err)
+	s.Equal(1, len(services))
+	s.Equal("myService", services[0].ServiceName)
+}
+
+func (s *ProviderFileTestSuite) Test_List_ReturnsError_WhenDirDoesNotExist() {
+	_, err := NewFileProvider("/this/dir/does/not/exist").List()
+
+	s.Error(err)
+}
+
+func (s *ProviderFileTestSuite) Test_Events_ReportsServiceName_WhenRuleFileIsRemoved() {
+	path := s.Dir + "/myService.yml"
+	content := []byte("serviceName: myService\nservicePath:\n  - /api/x\n")
+
+	p := NewFileProvider(s.Dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := p.Events(ctx)
+	// Give the watcher a moment to start before the file is created, or
+	// the Create event below would never be seen.
+	time.Sleep(100 * time.Millisecond)
+	s.NoError(ioutil.WriteFile(path, content, 0644))
+
+	// Wait for the Create event to be seen (and the service name cached)
+	// before deleting the file, the same way a real rolling deploy would
+	// first write the rule file and only remove it later.
+	select {
+	case id := <-out:
+		s.Equal("myService", id.ServiceName)
+	case <-time.After(5 * time.Second):
+		s.Fail("did not observe the create event for the rule file")
+	}
+
+	s.NoError(os.Remove(path))
+
+	select {
+	case id := <-out:
+		s.Equal("myService", id.ServiceName)
+	case <-time.After(5 * time.Second):
+		s.Fail("Events did not report a ProviderID for the removed rule file")
+	}
+}
+
+func TestProviderFileTestSuite(t *testing.T) {
+	suite.Run(t, new(ProviderFileTestSuite))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Proxy is implemented by types that can turn the rendered consul-template
+// output into a running HAProxy configuration.
+type Proxy interface {
+	CreateConfigFromTemplates(templatesPath, configsPath string) error
+	Reload() error
+	// Rollback restores the most recently snapshotted known-good
+	// configuration, reporting whether one was available to restore.
+	Rollback(configsPath string) (bool, error)
+}
+
+// HaProxy is the default Proxy implementation. It renders all the
+// per-service template fragments found in templatesPath into a single
+// haproxy.cfg inside configsPath and reloads the running HAProxy process.
+type HaProxy struct{}
+
+// NewProxy creates a new HaProxy-backed Proxy.
+func NewProxy() Proxy {
+	return HaProxy{}
+}
+
+// CreateConfigFromTemplates renders the templates, validates the result
+// with `haproxy -c` in a temp file, and only on success snapshots the
+// previous config and atomically swaps the new one into place. A bad
+// render never touches the live haproxy.cfg.
+func (p HaProxy) CreateConfigFromTemplates(templatesPath, configsPath string) error {
+	configPath := fmt.Sprintf("%s/haproxy.cfg", configsPath)
+	tmpPath := fmt.Sprintf("%s.tmp", configPath)
+	content, err := readConfigsContent(templatesPath)
+	if err != nil {
+		return fmt.Errorf("Could not read the templates directory %s\n%s", templatesPath, err.Error())
+	}
+	if certs := certManager.List(); len(certs) > 0 {
+		// getFrontTemplate/getBackTemplate/readConfigsContent never emit a
+		// frontend/listen section header of their own - every fragment
+		// they produce is only acl/use_backend/backend lines, meant to be
+		// concatenated into a frontend opened by a base template elsewhere
+		// in templatesPath. A bare `bind` line outside any such block is
+		// invalid HAProxy syntax, so the SSL bind gets its own self
+		// contained frontend instead of being prepended as a top-level
+		// directive.
+		https := []byte(fmt.Sprintf("\nfrontend https-in\n    bind *:443 ssl crt %s\n", certManager.CrtListPath()))
+		content = append(https, content...)
+	}
+	if err := writeConsulTemplateFile(tmpPath, content, 0664); err != nil {
+		return fmt.Errorf("Could not write the configuration to %s\n%s", tmpPath, err.Error())
+	}
+	if err := validateHaConfig(tmpPath); err != nil {
+		removeFile(tmpPath)
+		return fmt.Errorf("The rendered HAProxy configuration is invalid, keeping the previous one\n%s", err.Error())
+	}
+	if err := snapshotConfig(configPath); err != nil {
+		logPrintf("Could not snapshot the previous configuration: %s", err.Error())
+	}
+	if err := renameFile(tmpPath, configPath); err != nil {
+		return fmt.Errorf("Could not activate the validated configuration at %s\n%s", configPath, err.Error())
+	}
+	return nil
+}
+
+// Rollback implements Proxy.
+func (p HaProxy) Rollback(configsPath string) (bool, error) {
+	configPath := fmt.Sprintf("%s/haproxy.cfg", configsPath)
+	return rollbackConfig(configPath)
+}
+
+var cmdRunHaValidate = func(cmd *exec.Cmd) error {
+	return cmd.Run()
+}
+
+func validateHaConfig(path string) error {
+	cmd := exec.Command("haproxy", "-c", "-f", path)
+	return cmdRunHaValidate(cmd)
+}
+
+var renameFile = func(oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+func (p HaProxy) Reload() error {
+	pidBytes, err := readPidFile("/var/run/haproxy.pid")
+	if err != nil {
+		return fmt.Errorf("Could not read the HAProxy pid file\n%s", err.Error())
+	}
+	cmd := exec.Command("haproxy", "-f", "/cfg/haproxy.cfg", "-p", "/var/run/haproxy.pid", "-sf", string(pidBytes))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmdRunHa(cmd); err != nil {
+		return fmt.Errorf("Could not reload HAProxy\n%s", err.Error())
+	}
+	return nil
+}
+
+var readConfigsContent = func(templatesPath string) ([]byte, error) {
+	dir, err := os.Open(templatesPath)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	var content []byte
+	for _, name := range names {
+		data, err := readTemplateFile(fmt.Sprintf("%s/%s", templatesPath, name))
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, data...)
+	}
+	return content, nil
+}
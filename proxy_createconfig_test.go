@@ -0,0 +1,97 @@
+// +build !integration
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HaProxyCreateConfigTestSuite struct {
+	suite.Suite
+	TemplatesPath string
+	ConfigsPath   string
+	proxy         HaProxy
+}
+
+func (s *HaProxyCreateConfigTestSuite) SetupTest() {
+	s.TemplatesPath = "test_configs/tmpl"
+	s.ConfigsPath = "/cfg"
+	s.proxy = HaProxy{}
+	readTemplateFile = func(fileName string) ([]byte, error) {
+		return []byte("fragment"), nil
+	}
+	writeConsulTemplateFile = func(fileName string, data []byte, perm os.FileMode) error {
+		return nil
+	}
+	removeFile = func(path string) error {
+		return nil
+	}
+	renameFile = func(oldPath, newPath string) error {
+		return nil
+	}
+	cmdRunHaValidate = func(cmd *exec.Cmd) error {
+		return nil
+	}
+}
+
+func (s *HaProxyCreateConfigTestSuite) Test_CreateConfigFromTemplates_ReturnsError_WhenValidationFails_AndDoesNotSwapConfig() {
+	cmdRunHaValidate = func(cmd *exec.Cmd) error {
+		return fmt.Errorf("this is an error")
+	}
+	var removed, renamed bool
+	removeFile = func(path string) error {
+		removed = true
+		s.Equal(fmt.Sprintf("%s/haproxy.cfg.tmp", s.ConfigsPath), path)
+		return nil
+	}
+	renameFile = func(oldPath, newPath string) error {
+		renamed = true
+		return nil
+	}
+
+	err := s.proxy.CreateConfigFromTemplates(s.TemplatesPath, s.ConfigsPath)
+
+	s.Error(err)
+	s.True(removed)
+	s.False(renamed)
+}
+
+func (s *HaProxyCreateConfigTestSuite) Test_CreateConfigFromTemplates_SnapshotsThenSwapsConfig_WhenValidationSucceeds() {
+	snapshotClock = func() int64 {
+		return 1
+	}
+	readTemplateFile = func(fileName string) ([]byte, error) {
+		if fileName == fmt.Sprintf("%s/haproxy.cfg", s.ConfigsPath) {
+			return []byte("previous config"), nil
+		}
+		return []byte("fragment"), nil
+	}
+	var snapshotted bool
+	var renamedFrom, renamedTo string
+	writeConsulTemplateFile = func(fileName string, data []byte, perm os.FileMode) error {
+		if fileName == fmt.Sprintf("%s/haproxy.cfg.1", s.ConfigsPath) {
+			snapshotted = true
+		}
+		return nil
+	}
+	renameFile = func(oldPath, newPath string) error {
+		renamedFrom, renamedTo = oldPath, newPath
+		return nil
+	}
+
+	err := s.proxy.CreateConfigFromTemplates(s.TemplatesPath, s.ConfigsPath)
+
+	s.NoError(err)
+	s.True(snapshotted)
+	s.Equal(fmt.Sprintf("%s/haproxy.cfg.tmp", s.ConfigsPath), renamedFrom)
+	s.Equal(fmt.Sprintf("%s/haproxy.cfg", s.ConfigsPath), renamedTo)
+}
+
+func TestHaProxyCreateConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(HaProxyCreateConfigTestSuite))
+}
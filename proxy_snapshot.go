@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxConfigSnapshots is how many known-good haproxy.cfg snapshots are kept
+// on disk, so operators can roll back after a bad deploy slips past
+// validation in some way validateHaConfig doesn't catch.
+const maxConfigSnapshots = 5
+
+// snapshotConfig copies the current, already-validated config at
+// configPath into a timestamped snapshot alongside it and prunes old ones
+// beyond maxConfigSnapshots. It's a no-op the first time, before any
+// config has been written yet.
+func snapshotConfig(configPath string) error {
+	content, err := readTemplateFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	snapshotPath := fmt.Sprintf("%s.%d", configPath, snapshotClock())
+	if err := writeConsulTemplateFile(snapshotPath, content, 0664); err != nil {
+		return fmt.Errorf("Could not write the snapshot %s\n%s", snapshotPath, err.Error())
+	}
+	return pruneSnapshots(configPath)
+}
+
+// snapshotClock returns the value used to name the next snapshot. It's a
+// var so tests can make snapshot ordering deterministic instead of relying
+// on real time.
+var snapshotClock = func() int64 {
+	return time.Now().UnixNano()
+}
+
+func pruneSnapshots(configPath string) error {
+	paths, err := listSnapshots(configPath)
+	if err != nil {
+		return err
+	}
+	for len(paths) > maxConfigSnapshots {
+		if err := removeFile(paths[0]); err != nil {
+			return err
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
+
+// listSnapshots returns configPath's snapshots, oldest first.
+func listSnapshots(configPath string) ([]string, error) {
+	dir, err := os.Open(snapshotDir(configPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	prefix := snapshotBase(configPath) + "."
+	type snapshot struct {
+		path  string
+		clock int64
+	}
+	var snapshots []snapshot
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		clock, err := strconv.ParseInt(strings.TrimPrefix(name, prefix), 10, 64)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot{path: fmt.Sprintf("%s/%s", snapshotDir(configPath), name), clock: clock})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].clock < snapshots[j].clock })
+	paths := make([]string, len(snapshots))
+	for i, s := range snapshots {
+		paths[i] = s.path
+	}
+	return paths, nil
+}
+
+func snapshotDir(configPath string) string {
+	if idx := strings.LastIndex(configPath, "/"); idx >= 0 {
+		return configPath[:idx]
+	}
+	return "."
+}
+
+func snapshotBase(configPath string) string {
+	if idx := strings.LastIndex(configPath, "/"); idx >= 0 {
+		return configPath[idx+1:]
+	}
+	return configPath
+}
+
+// rollbackConfig restores the most recent snapshot of configPath, if any,
+// and reports whether one was found.
+func rollbackConfig(configPath string) (bool, error) {
+	paths, err := listSnapshots(configPath)
+	if err != nil {
+		return false, err
+	}
+	if len(paths) == 0 {
+		return false, nil
+	}
+	latest := paths[len(paths)-1]
+	content, err := readTemplateFile(latest)
+	if err != nil {
+		return false, err
+	}
+	if err := writeConsulTemplateFile(configPath, content, 0664); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,79 @@
+// +build !integration
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ProxySnapshotTestSuite struct {
+	suite.Suite
+	ConfigPath string
+}
+
+func (s *ProxySnapshotTestSuite) SetupTest() {
+	s.ConfigPath = "/cfg/haproxy.cfg"
+	readTemplateFile = func(fileName string) ([]byte, error) {
+		return []byte("config"), nil
+	}
+	writeConsulTemplateFile = func(fileName string, data []byte, perm os.FileMode) error {
+		return nil
+	}
+	removeFile = func(path string) error {
+		return nil
+	}
+	snapshotClock = func() int64 {
+		return 1
+	}
+}
+
+func (s *ProxySnapshotTestSuite) Test_SnapshotConfig_WritesTimestampedCopy() {
+	var actual string
+	writeConsulTemplateFile = func(fileName string, data []byte, perm os.FileMode) error {
+		actual = fileName
+		return nil
+	}
+
+	err := snapshotConfig(s.ConfigPath)
+
+	s.NoError(err)
+	s.Equal(fmt.Sprintf("%s.1", s.ConfigPath), actual)
+}
+
+func (s *ProxySnapshotTestSuite) Test_SnapshotConfig_IsNoop_WhenConfigDoesNotExistYet() {
+	var called bool
+	readTemplateFile = func(fileName string) ([]byte, error) {
+		return nil, os.ErrNotExist
+	}
+	writeConsulTemplateFile = func(fileName string, data []byte, perm os.FileMode) error {
+		called = true
+		return nil
+	}
+
+	err := snapshotConfig(s.ConfigPath)
+
+	s.NoError(err)
+	s.False(called)
+}
+
+func (s *ProxySnapshotTestSuite) Test_ListSnapshots_ReturnsNil_WhenDirDoesNotExist() {
+	paths, err := listSnapshots("/this/does/not/exist/haproxy.cfg")
+
+	s.NoError(err)
+	s.Nil(paths)
+}
+
+func (s *ProxySnapshotTestSuite) Test_RollbackConfig_ReturnsFalse_WhenNoSnapshotsExist() {
+	restored, err := rollbackConfig("/this/does/not/exist/haproxy.cfg")
+
+	s.NoError(err)
+	s.False(restored)
+}
+
+func TestProxySnapshotTestSuite(t *testing.T) {
+	suite.Run(t, new(ProxySnapshotTestSuite))
+}
@@ -0,0 +1,40 @@
+// +build !integration
+
+package main
+
+import "github.com/stretchr/testify/mock"
+
+// ProxyMock
+
+type ProxyMock struct {
+	mock.Mock
+}
+
+func (m *ProxyMock) CreateConfigFromTemplates(templatesPath, configsPath string) error {
+	params := m.Called(templatesPath, configsPath)
+	return params.Error(0)
+}
+
+func (m *ProxyMock) Reload() error {
+	params := m.Called()
+	return params.Error(0)
+}
+
+func (m *ProxyMock) Rollback(configsPath string) (bool, error) {
+	params := m.Called(configsPath)
+	return params.Bool(0), params.Error(1)
+}
+
+func getProxyMock(skipMethod string) *ProxyMock {
+	mockObj := new(ProxyMock)
+	if skipMethod != "CreateConfigFromTemplates" {
+		mockObj.On("CreateConfigFromTemplates", mock.Anything, mock.Anything).Return(nil)
+	}
+	if skipMethod != "Reload" {
+		mockObj.On("Reload").Return(nil)
+	}
+	if skipMethod != "Rollback" {
+		mockObj.On("Rollback", mock.Anything).Return(false, nil)
+	}
+	return mockObj
+}
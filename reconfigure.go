@@ -0,0 +1,363 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/tlebovic/docker-flow-proxy/cert"
+	"github.com/tlebovic/docker-flow-proxy/renderer"
+)
+
+// Keys used to store/retrieve per-service data in Consul's KV store under
+// the docker-flow/<service> prefix.
+const (
+	PATH_KEY       = "path"
+	COLOR_KEY      = "color"
+	DOMAIN_KEY     = "domain"
+	PATH_TYPE_KEY  = "pathtype"
+	SKIP_CHECK_KEY = "skipcheck"
+	CERT_KEY       = "cert"
+)
+
+// ServiceTemplateFeFilename and ServiceTemplateBeFilename are the names of
+// the consul-template source files Execute writes before invoking
+// consul-template to render them into the service's frontend/backend
+// config fragments.
+const (
+	ServiceTemplateFeFilename = "service-formatted-fe.ctmpl"
+	ServiceTemplateBeFilename = "service-formatted-be.ctmpl"
+)
+
+var cmdRunConsul = func(cmd *exec.Cmd) error {
+	return cmd.Run()
+}
+
+var cmdRunHa = func(cmd *exec.Cmd) error {
+	return cmd.Run()
+}
+
+var readPidFile = func(fileName string) ([]byte, error) {
+	return ioutil.ReadFile(fileName)
+}
+
+var writeConsulTemplateFile = func(fileName string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(fileName, data, perm)
+}
+
+var readTemplateFile = func(fileName string) ([]byte, error) {
+	return ioutil.ReadFile(fileName)
+}
+
+var logPrintf = func(format string, v ...interface{}) {
+	fmt.Printf(format+"\n", v...)
+}
+
+var proxy = NewProxy()
+
+// templateRenderer turns the consul-template source GetConsulTemplate
+// produces into the rendered HAProxy config fragment. It defaults to
+// ConsulTemplateRenderer so existing deploys keep shelling out to the
+// consul-template binary; set it to renderer.NewNativeRenderer() to render
+// templates in-process against Consul's HTTP API instead.
+var templateRenderer renderer.TemplateRenderer = ConsulTemplateRenderer{}
+
+// certManager obtains and tracks the certificates services request via
+// ServiceCert (serviceCert=auto or a PEM path), and keeps HAProxy's
+// crt-list in sync with them. It's replaced with a Manager pointed at the
+// configured certs directory in main.
+var certManager = cert.NewManager("/certs")
+
+// BaseReconfigure holds the settings that apply regardless of the service
+// being (re)configured: where consul-template output and the rendered
+// HAProxy configuration live, and how to reach Consul.
+type BaseReconfigure struct {
+	ConsulAddress string
+	TemplatesPath string
+	ConfigsPath   string
+}
+
+// ServiceReconfigure holds everything needed to turn a single service into
+// HAProxy frontend/backend configuration.
+type ServiceReconfigure struct {
+	ServiceName          string
+	ServiceColor         string
+	ServiceDomain        string
+	ServicePath          []string
+	PathType             string
+	SkipCheck            bool
+	ConsulTemplateFePath string
+	ConsulTemplateBePath string
+	ServiceCert          string
+}
+
+// Reconfigurable is implemented by anything that can turn a
+// BaseReconfigure/ServiceReconfigure pair into a running proxy
+// configuration.
+type Reconfigurable interface {
+	Execute(args []string) error
+	GetData() (BaseReconfigure, ServiceReconfigure)
+	ReloadAllServices(address string) error
+	GetConsulTemplate(sr ServiceReconfigure) (front, back string, err error)
+}
+
+// Reconfigure is the default Reconfigurable implementation, driven by
+// consul-template and a pluggable Proxy.
+type Reconfigure struct {
+	BaseReconfigure
+	ServiceReconfigure
+}
+
+// NewReconfigure creates a Reconfigurable for the given base/service
+// settings.
+func NewReconfigure(baseData BaseReconfigure, serviceData ServiceReconfigure) Reconfigurable {
+	return &Reconfigure{BaseReconfigure: baseData, ServiceReconfigure: serviceData}
+}
+
+func (r Reconfigure) GetData() (BaseReconfigure, ServiceReconfigure) {
+	return r.BaseReconfigure, r.ServiceReconfigure
+}
+
+// Execute renders the consul-template source for this service through the
+// configured templateRenderer, asks the Proxy to rebuild its configuration
+// from the rendered templates, reloads it, and finally records the
+// service's settings in Consul so that they survive a restart.
+func (r Reconfigure) Execute(args []string) error {
+	if len(r.ServiceCert) > 0 {
+		if err := certManager.Add(r.ServiceDomain, r.ServiceCert); err != nil {
+			return err
+		}
+	}
+	front, back, err := r.GetConsulTemplate(r.ServiceReconfigure)
+	if err != nil {
+		return err
+	}
+	if err := templateRenderer.Render(r.ConsulAddress, r.TemplatesPath, r.ConfigsPath, r.ServiceName, front, back); err != nil {
+		return err
+	}
+	if err := proxy.CreateConfigFromTemplates(r.TemplatesPath, r.ConfigsPath); err != nil {
+		return err
+	}
+	if err := proxy.Reload(); err != nil {
+		return err
+	}
+	return r.putToConsul()
+}
+
+// ReloadAllServices rebuilds the proxy configuration for every service
+// currently registered in Consul's catalog, rather than just the one this
+// Reconfigure was created for.
+func (r Reconfigure) ReloadAllServices(address string) error {
+	address = addHttpIfNeeded(address)
+	services, err := r.getCatalogServices(address)
+	if err != nil {
+		return err
+	}
+	var fronts, backs []string
+	for _, serviceName := range services {
+		sr, err := r.getServiceFromConsul(address, serviceName)
+		if err != nil {
+			// The service has no docker-flow-proxy data stored in Consul, skip it.
+			continue
+		}
+		front, back, err := r.GetConsulTemplate(sr)
+		if err != nil {
+			continue
+		}
+		fronts = append(fronts, front)
+		backs = append(backs, back)
+	}
+	if err := writeTemplateFiles(r.TemplatesPath, strings.Join(fronts, "\n"), strings.Join(backs, "\n")); err != nil {
+		return err
+	}
+	if err := proxy.CreateConfigFromTemplates(r.TemplatesPath, r.ConfigsPath); err != nil {
+		return err
+	}
+	return proxy.Reload()
+}
+
+// GetConsulTemplate produces the consul-template source for a service's
+// frontend and backend, unless a custom template file was provided for
+// either, in which case its content is used verbatim.
+func (r Reconfigure) GetConsulTemplate(sr ServiceReconfigure) (front, back string, err error) {
+	front, err = r.getFrontTemplate(sr)
+	if err != nil {
+		return "", "", err
+	}
+	back, err = r.getBackTemplate(sr)
+	if err != nil {
+		return "", "", err
+	}
+	return front, back, nil
+}
+
+func (r Reconfigure) getFrontTemplate(sr ServiceReconfigure) (string, error) {
+	if len(sr.ConsulTemplateFePath) > 0 {
+		content, err := readTemplateFile(sr.ConsulTemplateFePath)
+		if err != nil {
+			return "", fmt.Errorf("Could not read the Consul Template frontend file %s\n%s", sr.ConsulTemplateFePath, err.Error())
+		}
+		return string(content), nil
+	}
+	pathType := sr.PathType
+	if len(pathType) == 0 {
+		pathType = "path_beg"
+	}
+	aclName := fmt.Sprintf("url_%s", sr.ServiceName)
+	paths := []string{}
+	for _, path := range sr.ServicePath {
+		paths = append(paths, fmt.Sprintf("%s %s", pathType, path))
+	}
+	tmpl := fmt.Sprintf("\n    acl %s %s", aclName, strings.Join(paths, " "))
+	useBackend := aclName
+	if len(sr.ServiceDomain) > 0 {
+		domainAcl := fmt.Sprintf("domain_%s", sr.ServiceName)
+		tmpl += fmt.Sprintf("\n    acl %s hdr_dom(host) -i %s", domainAcl, sr.ServiceDomain)
+		useBackend += " " + domainAcl
+	}
+	if len(sr.ServiceCert) > 0 && len(sr.ServiceDomain) > 0 {
+		sniAcl := fmt.Sprintf("sni_%s", sr.ServiceName)
+		tmpl += fmt.Sprintf("\n    acl %s req.ssl_sni -i %s", sniAcl, sr.ServiceDomain)
+		useBackend += " " + sniAcl
+	}
+	tmpl += fmt.Sprintf("\n    use_backend %s-be if %s", sr.ServiceName, useBackend)
+	return tmpl, nil
+}
+
+func (r Reconfigure) getBackTemplate(sr ServiceReconfigure) (string, error) {
+	if len(sr.ConsulTemplateBePath) > 0 {
+		content, err := readTemplateFile(sr.ConsulTemplateBePath)
+		if err != nil {
+			return "", fmt.Errorf("Could not read the Consul Template backend file %s\n%s", sr.ConsulTemplateBePath, err.Error())
+		}
+		return string(content), nil
+	}
+	lookupName := sr.ServiceName
+	if len(sr.ServiceColor) > 0 {
+		lookupName = fmt.Sprintf("%s-%s", sr.ServiceName, sr.ServiceColor)
+	}
+	check := " check"
+	if sr.SkipCheck {
+		check = ""
+	}
+	tmpl := fmt.Sprintf(
+		`backend %s-be
+    {{range $i, $e := service "%s" "any"}}
+    server {{$e.Node}}_{{$i}}_{{$e.Port}} {{$e.Address}}:{{$e.Port}}%s
+    {{end}}`,
+		sr.ServiceName,
+		lookupName,
+		check,
+	)
+	return tmpl, nil
+}
+
+func (r Reconfigure) putToConsul() error {
+	address := addHttpIfNeeded(r.ConsulAddress)
+	sr := r.ServiceReconfigure
+	values := map[string]string{
+		COLOR_KEY:              sr.ServiceColor,
+		PATH_KEY:               strings.Join(sr.ServicePath, ","),
+		DOMAIN_KEY:             sr.ServiceDomain,
+		PATH_TYPE_KEY:          sr.PathType,
+		SKIP_CHECK_KEY:         fmt.Sprintf("%t", sr.SkipCheck),
+		"consultemplatefepath": sr.ConsulTemplateFePath,
+		"consultemplatebepath": sr.ConsulTemplateBePath,
+		CERT_KEY:               sr.ServiceCert,
+	}
+	for key, value := range values {
+		url := fmt.Sprintf("%s/v1/kv/docker-flow/%s/%s", address, sr.ServiceName, key)
+		req, err := http.NewRequest("PUT", url, strings.NewReader(value))
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("Could not send %s to Consul\n%s", key, err.Error())
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+func (r Reconfigure) getCatalogServices(address string) ([]string, error) {
+	url := fmt.Sprintf("%s/v1/catalog/services", address)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string][]string{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	services := []string{}
+	for name := range data {
+		services = append(services, name)
+	}
+	return services, nil
+}
+
+func (r Reconfigure) getServiceFromConsul(address, serviceName string) (ServiceReconfigure, error) {
+	path, err := r.getConsulValue(address, serviceName, PATH_KEY)
+	if err != nil {
+		return ServiceReconfigure{}, err
+	}
+	color, _ := r.getConsulValue(address, serviceName, COLOR_KEY)
+	domain, _ := r.getConsulValue(address, serviceName, DOMAIN_KEY)
+	pathType, _ := r.getConsulValue(address, serviceName, PATH_TYPE_KEY)
+	skipCheckValue, _ := r.getConsulValue(address, serviceName, SKIP_CHECK_KEY)
+	serviceCert, _ := r.getConsulValue(address, serviceName, CERT_KEY)
+	return ServiceReconfigure{
+		ServiceName:   serviceName,
+		ServicePath:   strings.Split(path, ","),
+		ServiceColor:  color,
+		ServiceDomain: domain,
+		PathType:      pathType,
+		SkipCheck:     skipCheckValue == "true",
+		ServiceCert:   serviceCert,
+	}, nil
+}
+
+func (r Reconfigure) getConsulValue(address, serviceName, key string) (string, error) {
+	url := fmt.Sprintf("%s/v1/kv/docker-flow/%s/%s?raw", address, serviceName, key)
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Consul returned status %d for %s", resp.StatusCode, url)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func addHttpIfNeeded(address string) string {
+	if !strings.HasPrefix(strings.ToLower(address), "http") {
+		return fmt.Sprintf("http://%s", address)
+	}
+	return address
+}
+
+var schemeRegex = regexp.MustCompile(`(?i)https?://`)
+
+// stripScheme removes every http(s):// occurrence from address, not just a
+// single leading one, since callers may pass an address that already went
+// through addHttpIfNeeded (or was otherwise double-prefixed) before
+// reaching here.
+func stripScheme(address string) string {
+	return schemeRegex.ReplaceAllString(address, "")
+}
@@ -0,0 +1,48 @@
+// +build !integration
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ReconfigureCertTestSuite struct {
+	suite.Suite
+}
+
+func (s *ReconfigureCertTestSuite) Test_GetConsulTemplate_AddsSniAcl_WhenServiceCertAndDomainSet() {
+	r := Reconfigure{}
+	sr := ServiceReconfigure{
+		ServiceName:   "myService",
+		ServicePath:   []string{"/api"},
+		ServiceDomain: "foo.com",
+		ServiceCert:   "auto",
+	}
+
+	front, _, err := r.GetConsulTemplate(sr)
+
+	s.NoError(err)
+	s.True(strings.Contains(front, "acl sni_myService req.ssl_sni -i foo.com"))
+	s.True(strings.Contains(front, "use_backend myService-be if url_myService domain_myService sni_myService"))
+}
+
+func (s *ReconfigureCertTestSuite) Test_GetConsulTemplate_NoSniAcl_WhenServiceCertNotSet() {
+	r := Reconfigure{}
+	sr := ServiceReconfigure{
+		ServiceName:   "myService",
+		ServicePath:   []string{"/api"},
+		ServiceDomain: "foo.com",
+	}
+
+	front, _, err := r.GetConsulTemplate(sr)
+
+	s.NoError(err)
+	s.False(strings.Contains(front, "req.ssl_sni"))
+}
+
+func TestReconfigureCertTestSuite(t *testing.T) {
+	suite.Run(t, new(ReconfigureCertTestSuite))
+}
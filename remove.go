@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Remove removes a single service's configuration from the proxy and
+// reloads it.
+type Remove struct {
+	ServiceName   string
+	ServiceDomain string
+	TemplatesPath string
+	ConfigsPath   string
+}
+
+// Execute deletes the service's rendered config fragments, forgets any
+// certificate issued for its domain, and asks the Proxy to rebuild and
+// reload its configuration without them.
+func (r Remove) Execute(args []string) error {
+	fePath := fmt.Sprintf("%s/%s-fe.cfg", r.TemplatesPath, r.ServiceName)
+	bePath := fmt.Sprintf("%s/%s-be.cfg", r.TemplatesPath, r.ServiceName)
+	for _, path := range []string{fePath, bePath} {
+		if err := removeFile(path); err != nil {
+			return fmt.Errorf("Could not remove the configuration for %s\n%s", r.ServiceName, err.Error())
+		}
+	}
+	if len(r.ServiceDomain) > 0 {
+		if err := certManager.Remove(r.ServiceDomain); err != nil {
+			return err
+		}
+	}
+	if err := proxy.CreateConfigFromTemplates(r.TemplatesPath, r.ConfigsPath); err != nil {
+		return err
+	}
+	return proxy.Reload()
+}
+
+var removeFile = func(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
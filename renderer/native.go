@@ -0,0 +1,162 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// ServiceEntry is what the `service` template function yields for each
+// passing instance of a service, mirroring the fields consul-template's
+// own `service` function exposes (`{{$e.Node}}`, `{{$e.Address}}`,
+// `{{$e.Port}}`).
+type ServiceEntry struct {
+	Node    string
+	Address string
+	Port    int
+	Tags    []string
+}
+
+// NativeRenderer renders a service's consul-template source with Go's
+// text/template instead of shelling out to the consul-template binary,
+// fetching the backing service data directly from Consul's HTTP API
+// (`/v1/health/service/<name>`, with `?passing` unless the tag argument is
+// "any"; see service). It supports the same
+// `{{range service "x" "any"}}` idiom used by ConsulTemplateRenderer, plus
+// keyOrDefault, env and byTag.
+type NativeRenderer struct {
+	HTTPGet func(url string) (*http.Response, error)
+}
+
+// NewNativeRenderer creates a NativeRenderer that talks to Consul over
+// plain net/http.
+func NewNativeRenderer() *NativeRenderer {
+	return &NativeRenderer{HTTPGet: http.Get}
+}
+
+// Render implements TemplateRenderer.
+func (n *NativeRenderer) Render(consulAddress, templatesPath, configsPath, serviceName, front, back string) error {
+	funcs := n.funcMap(consulAddress)
+	renderedFront, err := render(front, funcs)
+	if err != nil {
+		return fmt.Errorf("Could not render the frontend template for %s\n%s", serviceName, err.Error())
+	}
+	renderedBack, err := render(back, funcs)
+	if err != nil {
+		return fmt.Errorf("Could not render the backend template for %s\n%s", serviceName, err.Error())
+	}
+	fePath := fmt.Sprintf("%s/%s-fe.cfg", templatesPath, serviceName)
+	bePath := fmt.Sprintf("%s/%s-be.cfg", templatesPath, serviceName)
+	if err := ioutil.WriteFile(fePath, []byte(renderedFront), 0664); err != nil {
+		return fmt.Errorf("Could not write %s\n%s", fePath, err.Error())
+	}
+	if err := ioutil.WriteFile(bePath, []byte(renderedBack), 0664); err != nil {
+		return fmt.Errorf("Could not write %s\n%s", bePath, err.Error())
+	}
+	return nil
+}
+
+func render(source string, funcs template.FuncMap) (string, error) {
+	tmpl, err := template.New("fragment").Funcs(funcs).Parse(source)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, nil); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func (n *NativeRenderer) funcMap(consulAddress string) template.FuncMap {
+	return template.FuncMap{
+		"service":      n.service(consulAddress),
+		"keyOrDefault": n.keyOrDefault(consulAddress),
+		"env":          os.Getenv,
+		"byTag":        byTag,
+	}
+}
+
+// service fetches a Consul service's instances. The second argument
+// mirrors consul-template's own `service "name" "tag"` signature: "any"
+// means every health status, same as consul-template, while any other tag
+// (or none at all) still only returns passing instances, with the actual
+// tag filtering left to byTag.
+func (n *NativeRenderer) service(consulAddress string) func(name string, tag ...string) ([]ServiceEntry, error) {
+	return func(name string, tag ...string) ([]ServiceEntry, error) {
+		url := fmt.Sprintf("%s/v1/health/service/%s", consulAddress, name)
+		if len(tag) == 0 || tag[0] != "any" {
+			url += "?passing"
+		}
+		resp, err := n.HTTPGet(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		var entries []struct {
+			Node struct {
+				Node    string
+				Address string
+			}
+			Service struct {
+				Port int
+				Tags []string
+			}
+		}
+		if err := json.Unmarshal(body, &entries); err != nil {
+			return nil, err
+		}
+		services := []ServiceEntry{}
+		for _, e := range entries {
+			services = append(services, ServiceEntry{
+				Node:    e.Node.Node,
+				Address: e.Node.Address,
+				Port:    e.Service.Port,
+				Tags:    e.Service.Tags,
+			})
+		}
+		return services, nil
+	}
+}
+
+func (n *NativeRenderer) keyOrDefault(consulAddress string) func(key, def string) string {
+	return func(key, def string) string {
+		url := fmt.Sprintf("%s/v1/kv/%s?raw", consulAddress, key)
+		resp, err := n.HTTPGet(url)
+		if err != nil {
+			return def
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return def
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil || len(body) == 0 {
+			return def
+		}
+		return string(body)
+	}
+}
+
+// byTag filters services down to the ones carrying tag, letting templates
+// do `{{range byTag "blue" (service "my-service" "any")}}`.
+func byTag(tag string, entries []ServiceEntry) []ServiceEntry {
+	filtered := []ServiceEntry{}
+	for _, e := range entries {
+		for _, t := range e.Tags {
+			if t == tag {
+				filtered = append(filtered, e)
+				break
+			}
+		}
+	}
+	return filtered
+}
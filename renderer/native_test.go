@@ -0,0 +1,103 @@
+// +build !integration
+
+package renderer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type NativeRendererTestSuite struct {
+	suite.Suite
+	Server        *httptest.Server
+	TemplatesPath string
+	renderer      *NativeRenderer
+}
+
+func (s *NativeRendererTestSuite) SetupTest() {
+	s.TemplatesPath = "."
+	s.renderer = NewNativeRenderer()
+	s.renderer.HTTPGet = http.Get
+}
+
+func (s *NativeRendererTestSuite) Test_Render_RendersServiceFunction() {
+	front := `{{range service "myService" "any"}}{{.Address}}:{{.Port}}{{end}}`
+	back := "backend"
+
+	err := s.renderer.Render(s.Server.URL, s.TemplatesPath, s.TemplatesPath, "myService", front, back)
+
+	s.NoError(err)
+	content, _ := ioutil.ReadFile(fmt.Sprintf("%s/myService-fe.cfg", s.TemplatesPath))
+	defer os.Remove(fmt.Sprintf("%s/myService-fe.cfg", s.TemplatesPath))
+	defer os.Remove(fmt.Sprintf("%s/myService-be.cfg", s.TemplatesPath))
+	s.Equal("1.2.3.4:1234", string(content))
+}
+
+func (s *NativeRendererTestSuite) Test_Render_ReturnsError_WhenTemplateIsInvalid() {
+	err := s.renderer.Render(s.Server.URL, s.TemplatesPath, s.TemplatesPath, "myService", "{{", "backend")
+
+	s.Error(err)
+}
+
+func (s *NativeRendererTestSuite) Test_Service_QueriesPassingOnly_ByDefault() {
+	var requestedURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedURL = r.URL.String()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	renderer := NewNativeRenderer()
+	renderer.HTTPGet = http.Get
+
+	_, err := renderer.funcMap(server.URL)["service"].(func(string, ...string) ([]ServiceEntry, error))("myService")
+
+	s.NoError(err)
+	s.True(strings.Contains(requestedURL, "passing"))
+}
+
+func (s *NativeRendererTestSuite) Test_Service_QueriesAnyStatus_WhenTagIsAny() {
+	var requestedURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedURL = r.URL.String()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	renderer := NewNativeRenderer()
+	renderer.HTTPGet = http.Get
+
+	_, err := renderer.funcMap(server.URL)["service"].(func(string, ...string) ([]ServiceEntry, error))("myService", "any")
+
+	s.NoError(err)
+	s.False(strings.Contains(requestedURL, "passing"))
+}
+
+func (s *NativeRendererTestSuite) Test_ByTag_FiltersByTag() {
+	entries := []ServiceEntry{
+		{Node: "node1", Tags: []string{"blue"}},
+		{Node: "node2", Tags: []string{"green"}},
+	}
+
+	actual := byTag("blue", entries)
+
+	s.Equal(1, len(actual))
+	s.Equal("node1", actual[0].Node)
+}
+
+func TestNativeRendererTestSuite(t *testing.T) {
+	s := new(NativeRendererTestSuite)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"Node":{"Node":"node1","Address":"1.2.3.4"},"Service":{"Port":1234,"Tags":[]}}]`))
+	}))
+	defer s.Server.Close()
+	suite.Run(t, s)
+}
@@ -0,0 +1,11 @@
+// Package renderer turns the consul-template source that Reconfigure
+// produces for a service's frontend/backend into the on-disk HAProxy
+// configuration fragment HAProxy actually reloads from.
+package renderer
+
+// TemplateRenderer is implemented by anything that can turn a service's
+// consul-template source (front and back) into its rendered
+// <templatesPath>/<serviceName>-fe.cfg and -be.cfg fragments.
+type TemplateRenderer interface {
+	Render(consulAddress, templatesPath, configsPath, serviceName, front, back string) error
+}
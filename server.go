@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Server exposes the docker-flow-proxy HTTP API used by deploy
+// orchestrators to trigger a reconfiguration.
+type Server struct {
+	BaseReconfigure
+}
+
+// NewServer creates a Server bound to the given base settings.
+func NewServer(base BaseReconfigure) *Server {
+	return &Server{BaseReconfigure: base}
+}
+
+// Execute registers the HTTP handlers and starts listening.
+func (s *Server) Execute() error {
+	http.HandleFunc("/v1/reconfigure", s.ReconfigureHandler)
+	http.HandleFunc("/v1/docker-flow-proxy/certs", s.CertsHandler)
+	http.HandleFunc("/v1/docker-flow-proxy/config/rollback", s.RollbackHandler)
+	logPrintf("Starting docker-flow-proxy")
+	return http.ListenAndServe(":8080", nil)
+}
+
+// ReconfigureHandler reconfigures the proxy for a single service, as
+// described by the request's query parameters.
+func (s *Server) ReconfigureHandler(w http.ResponseWriter, req *http.Request) {
+	sr := ServiceReconfigure{
+		ServiceName:   req.URL.Query().Get("serviceName"),
+		ServiceColor:  req.URL.Query().Get("serviceColor"),
+		ServiceDomain: req.URL.Query().Get("serviceDomain"),
+		PathType:      req.URL.Query().Get("pathType"),
+		ServiceCert:   req.URL.Query().Get("serviceCert"),
+	}
+	if path := req.URL.Query().Get("servicePath"); len(path) > 0 {
+		sr.ServicePath = []string{path}
+	}
+	reconfigure := NewReconfigure(s.BaseReconfigure, sr)
+	w.Header().Set("Content-Type", "application/json")
+	if err := reconfigure.Execute([]string{}); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"status": "NOK", "message": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "OK"})
+}
+
+// CertsHandler lists the certificates certManager currently knows about,
+// for operators inspecting what the proxy is terminating HTTPS with.
+func (s *Server) CertsHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(certManager.List())
+}
+
+// RollbackHandler restores the most recently snapshotted known-good
+// haproxy.cfg, for operators recovering from a bad deploy.
+func (s *Server) RollbackHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	restored, err := proxy.Rollback(s.ConfigsPath)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"status": "NOK", "message": err.Error()})
+		return
+	}
+	if !restored {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"status": "NOK", "message": "No configuration snapshot is available to restore"})
+		return
+	}
+	if err := proxy.Reload(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"status": "NOK", "message": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "OK"})
+}